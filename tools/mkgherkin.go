@@ -28,11 +28,30 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 	"encoding/json"
+	"encoding/xml"
 
+	"github.com/ghodss/yaml"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
 
 	spb "github.com/google/cel-spec/proto/test/v1/testpb"
 
@@ -43,13 +62,84 @@ import (
 	_ "github.com/google/cel-spec/proto/test/v1/proto3/test_all_types"
 )
 
+var input_format string
+
+func init() {
+	flag.StringVar(&input_format, "input-format", "", "Input format: prototext, binarypb or json (default: inferred from the file extension)")
+}
+
+// inputFormatFor picks the wire format to parse filename as: the
+// -input-format override if one was given, otherwise whatever the file
+// extension implies. "-" (stdin) has no extension to infer from, so it
+// requires -input-format.
+func inputFormatFor(filename string) (string, error) {
+	if input_format != "" {
+		return input_format, nil
+	}
+	switch {
+	case strings.HasSuffix(filename, ".textproto"), strings.HasSuffix(filename, ".pb.txt"):
+		return "prototext", nil
+	case strings.HasSuffix(filename, ".binarypb"), strings.HasSuffix(filename, ".pb"):
+		return "binarypb", nil
+	case strings.HasSuffix(filename, ".json"):
+		return "json", nil
+	}
+	return "", fmt.Errorf("cannot infer input format for %q; pass -input-format", filename)
+}
+
+// expandInputs expands shell-style globs in args so a single invocation
+// can fan out over e.g. tests/simple/testdata/*.textproto. "-" (stdin)
+// and any arg that doesn't match as a glob pass through unchanged, so a
+// literal filename that happens to contain glob metacharacters still
+// works as long as it is a well-formed pattern; a malformed pattern
+// (e.g. an unclosed "[") is returned as an error.
+func expandInputs(args []string) ([]string, error) {
+	var inputs []string
+	for _, arg := range args {
+		if arg == "-" {
+			inputs = append(inputs, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			inputs = append(inputs, arg)
+			continue
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
+
 func parseSimpleFile(filename string) (*spb.SimpleTestFile, error) {
-	bytes, err := ioutil.ReadFile(filename)
+	format, err := inputFormatFor(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if filename == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(filename)
+	}
 	if err != nil {
 		return nil, err
 	}
+
 	var pb spb.SimpleTestFile
-	err = prototext.Unmarshal(bytes, &pb)
+	switch format {
+	case "prototext":
+		err = prototext.Unmarshal(data, &pb)
+	case "binarypb":
+		err = proto.Unmarshal(data, &pb)
+	case "json":
+		err = protojson.Unmarshal(data, &pb)
+	default:
+		err = fmt.Errorf("unknown -input-format %q", format)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -66,14 +156,716 @@ func json_testfile(testFile *spb.SimpleTestFile) {
 	fmt.Println(json_formatter.Format(testFile))
 }
 
-func gherkin_testfile(testFile *spb.SimpleTestFile) {
-    // There are several kinds of "results" for a test.
-    //      *SimpleTest_Value -- these become a `Then value is ...` step.
-    //      *SimpleTest_EvalError -- these become a `Then eval_error is ...` step.
-    //      *SimpleTest_AnyEvalErrors -- these become a `Then eval_error is ...` step.
-    //      *SimpleTest_Unknown -- These don't seem to be used
-    //      *SimpleTest_AnyUnknowns -- These don't seem to be used
+// yamlScenario is one pytest-BDD / behave parametrization document: the
+// structured equivalent of a single Gherkin Scenario, carrying typed
+// fields instead of text. The json tags also drive YAML marshalling,
+// since ghodss/yaml round-trips through encoding/json.
+type yamlScenario struct {
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description,omitempty"`
+	DisableMacros bool                   `json:"disable_macros,omitempty"`
+	DisableCheck  bool                   `json:"disable_check,omitempty"`
+	TypeEnv       map[string]interface{} `json:"type_env,omitempty"`
+	Bindings      map[string]interface{} `json:"bindings,omitempty"`
+	Container     string                 `json:"container,omitempty"`
+	Expr          string                 `json:"expr"`
+	Expect        yamlExpect             `json:"expect"`
+}
+
+// yamlExpect mirrors the SimpleTest result_matcher oneof: exactly one of
+// these fields is populated, matching the oneof case the source
+// SimpleTest set.
+type yamlExpect struct {
+	Value       interface{}    `json:"value,omitempty"`
+	EvalError   *yamlEvalError `json:"eval_error,omitempty"`
+	Unknown     interface{}    `json:"unknown,omitempty"`
+	AnyUnknowns []interface{}  `json:"any_unknowns,omitempty"`
+}
+
+// yamlEvalError always carries an errors list, even for the single
+// EvalError case, so a step-definition layer has one shape to match
+// against regardless of whether the SimpleTest used eval_error or
+// any_eval_errors.
+type yamlEvalError struct {
+	Errors []interface{} `json:"errors"`
+}
+
+// protoToYAML converts a proto.Message into a generic Go value (map,
+// slice or scalar) by round-tripping it through protojson and
+// encoding/json, so yaml_testfile's output stays aligned with the field
+// names already used by the -json mode.
+func protoToYAML(msg proto.Message) (interface{}, error) {
+	if msg == nil || reflect.ValueOf(msg).IsNil() {
+		return nil, nil
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// declaredType extracts the "declared type" of a type_env Decl: the Type
+// of an IdentDecl, or the FunctionDecl itself when the Decl declares an
+// overload set instead of a variable.
+func declaredType(decl *exprpb.Decl) proto.Message {
+	if ident := decl.GetIdent(); ident != nil {
+		return ident.GetType()
+	}
+	if fn := decl.GetFunction(); fn != nil {
+		return fn
+	}
+	return nil
+}
+
+// resultExpect converts a SimpleTest's result_matcher oneof into the
+// yamlExpect shape described above.
+func resultExpect(test *spb.SimpleTest) (yamlExpect, error) {
+	switch {
+	case test.GetValue() != nil:
+		value, err := protoToYAML(test.GetValue())
+		return yamlExpect{Value: value}, err
+	case test.GetEvalError() != nil:
+		value, err := protoToYAML(test.GetEvalError())
+		if err != nil {
+			return yamlExpect{}, err
+		}
+		return yamlExpect{EvalError: &yamlEvalError{Errors: []interface{}{value}}}, nil
+	case test.GetAnyEvalErrors() != nil:
+		errors := make([]interface{}, 0, len(test.GetAnyEvalErrors().GetErrors()))
+		for _, sourceError := range test.GetAnyEvalErrors().GetErrors() {
+			value, err := protoToYAML(sourceError)
+			if err != nil {
+				return yamlExpect{}, err
+			}
+			errors = append(errors, value)
+		}
+		return yamlExpect{EvalError: &yamlEvalError{Errors: errors}}, nil
+	case test.GetUnknown() != nil:
+		value, err := protoToYAML(test.GetUnknown())
+		return yamlExpect{Unknown: value}, err
+	case test.GetAnyUnknowns() != nil:
+		unknowns := make([]interface{}, 0, len(test.GetAnyUnknowns().GetUnknowns()))
+		for _, sourceUnknown := range test.GetAnyUnknowns().GetUnknowns() {
+			value, err := protoToYAML(sourceUnknown)
+			if err != nil {
+				return yamlExpect{}, err
+			}
+			unknowns = append(unknowns, value)
+		}
+		return yamlExpect{AnyUnknowns: unknowns}, nil
+	}
+	return yamlExpect{}, fmt.Errorf("SimpleTest %q has no result_matcher set", test.GetName())
+}
 
+// yaml_testfile walks testFile and prints one YAML document per
+// Scenario, so a pytest-BDD / behave parametrization loop can consume
+// the conformance suite without re-parsing Gherkin step text.
+func yaml_testfile(testFile *spb.SimpleTestFile) {
+	for _, section := range testFile.GetSection() {
+		for _, test := range section.GetTest() {
+			scenario := yamlScenario{
+				Name:          test.GetName(),
+				Description:   test.GetDescription(),
+				DisableMacros: test.GetDisableMacros(),
+				DisableCheck:  test.GetDisableCheck(),
+				Container:     test.GetContainer(),
+				Expr:          test.GetExpr(),
+			}
+
+			if len(test.GetTypeEnv()) > 0 {
+				scenario.TypeEnv = make(map[string]interface{}, len(test.GetTypeEnv()))
+				for _, decl := range test.GetTypeEnv() {
+					declType, err := protoToYAML(declaredType(decl))
+					if err != nil {
+						panic(err)
+					}
+					scenario.TypeEnv[decl.GetName()] = declType
+				}
+			}
+
+			if len(test.GetBindings()) > 0 {
+				scenario.Bindings = make(map[string]interface{}, len(test.GetBindings()))
+				for name, binding := range test.GetBindings() {
+					value, err := protoToYAML(binding.GetValue())
+					if err != nil {
+						panic(err)
+					}
+					scenario.Bindings[name] = value
+				}
+			}
+
+			expect, err := resultExpect(test)
+			if err != nil {
+				panic(err)
+			}
+			scenario.Expect = expect
+
+			document, err := yaml.Marshal(scenario)
+			if err != nil {
+				panic(err)
+			}
+			fmt.Println("---")
+			fmt.Print(string(document))
+		}
+	}
+}
+
+// resultStep renders the `Then` step for a SimpleTest's result_matcher
+// oneof. Each case becomes its own distinct step, since a step-definition
+// layer needs to know up front whether it's matching a single value, one
+// of a set of errors, or an unknown result:
+//
+//	*SimpleTest_Value         -- `Then value is ...`
+//	*SimpleTest_EvalError     -- `Then eval_error is ...`
+//	*SimpleTest_AnyEvalErrors -- `Then eval_error is one of {...}`
+//	*SimpleTest_Unknown       -- `Then unknown is ...`
+//	*SimpleTest_AnyUnknowns   -- `Then any_unknown is one of {...}`
+func resultStep(test *spb.SimpleTest) (string, error) {
+	switch {
+	case test.GetValue() != nil:
+		return fmt.Sprintf("    Then value is %s", celValue(test.GetValue())), nil
+	case test.GetEvalError() != nil:
+		return fmt.Sprintf("    Then eval_error is %v", test.GetEvalError()), nil
+	case test.GetAnyEvalErrors() != nil:
+		return fmt.Sprintf("    Then eval_error is one of %v", test.GetAnyEvalErrors().GetErrors()), nil
+	case test.GetUnknown() != nil:
+		return fmt.Sprintf("    Then unknown is %v", test.GetUnknown()), nil
+	case test.GetAnyUnknowns() != nil:
+		return fmt.Sprintf("    Then any_unknown is one of %v", test.GetAnyUnknowns().GetUnknowns()), nil
+	}
+	return "", fmt.Errorf("SimpleTest %q has no result_matcher set", test.GetName())
+}
+
+// runCase pairs a SimpleTest with the name of the Section it came from,
+// so results can be reported and grouped the same way Gherkin groups
+// them into Features/Scenarios.
+type runCase struct {
+	Section string
+	Test    *spb.SimpleTest
+}
+
+// collectCases flattens a SimpleTestFile's Section/Test nesting into the
+// list run_conformance actually schedules.
+func collectCases(testFile *spb.SimpleTestFile) []runCase {
+	var cases []runCase
+	for _, section := range testFile.GetSection() {
+		for _, test := range section.GetTest() {
+			cases = append(cases, runCase{Section: section.GetName(), Test: test})
+		}
+	}
+	return cases
+}
+
+// runResult is the outcome of evaluating one SimpleTest against cel-go.
+// Err is non-nil when the environment, compilation or program planning
+// failed, or when the evaluation outcome (value or error) didn't match
+// the SimpleTest's result_matcher. Note carries a
+// diagnostic that applies even on success, e.g. that an any_eval_errors
+// match only checked that evaluation failed, not which error it failed
+// with.
+type runResult struct {
+	Section  string
+	Name     string
+	Duration time.Duration
+	Note     string
+	Err      error
+}
+
+// buildEnv configures a cel-go environment from a SimpleTest's Container,
+// DisableMacros and TypeEnv fields, so each test runs against the same
+// environment cel-python would build from those fields.
+func buildEnv(test *spb.SimpleTest) (*cel.Env, error) {
+	var opts []cel.EnvOption
+	if test.GetContainer() != "" {
+		opts = append(opts, cel.Container(test.GetContainer()))
+	}
+	if test.GetDisableMacros() {
+		opts = append(opts, cel.ClearMacros())
+	}
+
+	var declarations []*exprpb.Decl
+	for _, decl := range test.GetTypeEnv() {
+		if ident := decl.GetIdent(); ident != nil {
+			declarations = append(declarations, decls.NewVar(decl.GetName(), ident.GetType()))
+		}
+		if fn := decl.GetFunction(); fn != nil {
+			declarations = append(declarations, decls.NewFunction(decl.GetName(), fn.GetOverloads()...))
+		}
+	}
+	if len(declarations) > 0 {
+		opts = append(opts, cel.Declarations(declarations...))
+	}
+
+	return cel.NewEnv(opts...)
+}
+
+// valueToNative converts an exprpb.Value literal into the plain Go value
+// cel-go's activation expects, so a SimpleTest's Bindings and expected
+// Value can both be handed to the cel-go runtime without going through
+// CEL source text.
+func valueToNative(value *exprpb.Value) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch kind := value.GetKind().(type) {
+	case *exprpb.Value_NullValue:
+		return nil, nil
+	case *exprpb.Value_BoolValue:
+		return kind.BoolValue, nil
+	case *exprpb.Value_Int64Value:
+		return kind.Int64Value, nil
+	case *exprpb.Value_Uint64Value:
+		return kind.Uint64Value, nil
+	case *exprpb.Value_DoubleValue:
+		return kind.DoubleValue, nil
+	case *exprpb.Value_StringValue:
+		return kind.StringValue, nil
+	case *exprpb.Value_BytesValue:
+		return kind.BytesValue, nil
+	case *exprpb.Value_ListValue:
+		elements := make([]interface{}, 0, len(kind.ListValue.GetValues()))
+		for _, element := range kind.ListValue.GetValues() {
+			nativeElement, err := valueToNative(element)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, nativeElement)
+		}
+		return elements, nil
+	case *exprpb.Value_MapValue:
+		entries := make(map[interface{}]interface{}, len(kind.MapValue.GetEntries()))
+		for _, entry := range kind.MapValue.GetEntries() {
+			entryKey, err := valueToNative(entry.GetKey())
+			if err != nil {
+				return nil, err
+			}
+			entryValue, err := valueToNative(entry.GetValue())
+			if err != nil {
+				return nil, err
+			}
+			entries[entryKey] = entryValue
+		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("unsupported binding/result value %v", value)
+}
+
+// activationBindings converts a SimpleTest's Bindings map into the
+// map[string]interface{} cel-go's program.Eval expects as an Activation.
+func activationBindings(bindings map[string]*exprpb.ExprValue) (map[string]interface{}, error) {
+	native := make(map[string]interface{}, len(bindings))
+	for name, binding := range bindings {
+		value, err := valueToNative(binding.GetValue())
+		if err != nil {
+			return nil, fmt.Errorf("binding %q: %w", name, err)
+		}
+		native[name] = value
+	}
+	return native, nil
+}
+
+// checkResult compares a cel-go evaluation outcome against the
+// SimpleTest's result_matcher oneof. It returns a non-nil error
+// describing the mismatch when they disagree, and an optional note
+// describing any part of the match that was checked only loosely.
+func checkResult(test *spb.SimpleTest, out ref.Val, evalErr error) (string, error) {
+	switch {
+	case test.GetValue() != nil:
+		if evalErr != nil {
+			return "", fmt.Errorf("expected value %v, got error: %v", test.GetValue(), evalErr)
+		}
+		expected, err := valueToNative(test.GetValue())
+		if err != nil {
+			return "", err
+		}
+		if types.DefaultTypeAdapter.NativeToValue(expected).Equal(out) != types.True {
+			return "", fmt.Errorf("expected value %v, got %v", test.GetValue(), out)
+		}
+		return "", nil
+	case test.GetEvalError() != nil:
+		if evalErr == nil {
+			return "", fmt.Errorf("expected eval_error %v, got value %v", test.GetEvalError().GetErrors(), out)
+		}
+		for _, expected := range test.GetEvalError().GetErrors() {
+			if message := expected.GetMessage(); message == "" || strings.Contains(evalErr.Error(), message) {
+				return "", nil
+			}
+		}
+		return "", fmt.Errorf("expected one of eval_errors %v, got %q", test.GetEvalError().GetErrors(), evalErr.Error())
+	case test.GetAnyEvalErrors() != nil:
+		if evalErr == nil {
+			return "", fmt.Errorf("expected one of %d eval_errors, got value %v", len(test.GetAnyEvalErrors().GetErrors()), out)
+		}
+		return "any_eval_errors only checked that evaluation failed, not which of the listed errors it failed with", nil
+	case test.GetUnknown() != nil || test.GetAnyUnknowns() != nil:
+		if !types.IsUnknown(out) {
+			return "", fmt.Errorf("expected an unknown result, got %v (err: %v)", out, evalErr)
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("SimpleTest %q has no result_matcher set", test.GetName())
+}
+
+// evalSimpleTest builds an environment from test, compiles (or, when
+// DisableCheck is set, only parses) its Expr, evaluates it against its
+// Bindings and reports whether the outcome matched, plus any note
+// checkResult attached to that outcome.
+func evalSimpleTest(test *spb.SimpleTest) (string, error) {
+	env, err := buildEnv(test)
+	if err != nil {
+		return "", fmt.Errorf("building environment: %w", err)
+	}
+
+	var ast *cel.Ast
+	if test.GetDisableCheck() {
+		parsed, iss := env.Parse(test.GetExpr())
+		if iss != nil && iss.Err() != nil {
+			return "", fmt.Errorf("parsing %q: %w", test.GetExpr(), iss.Err())
+		}
+		ast = parsed
+	} else {
+		checked, iss := env.Compile(test.GetExpr())
+		if iss != nil && iss.Err() != nil {
+			return "", fmt.Errorf("compiling %q: %w", test.GetExpr(), iss.Err())
+		}
+		ast = checked
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return "", fmt.Errorf("planning %q: %w", test.GetExpr(), err)
+	}
+
+	bindings, err := activationBindings(test.GetBindings())
+	if err != nil {
+		return "", err
+	}
+
+	out, _, evalErr := program.Eval(bindings)
+	return checkResult(test, out, evalErr)
+}
+
+// evalOne runs one runCase and records how long it took, so the JUnit
+// report can carry per-test timings.
+func evalOne(testCase runCase) runResult {
+	start := time.Now()
+	note, err := evalSimpleTest(testCase.Test)
+	return runResult{
+		Section:  testCase.Section,
+		Name:     testCase.Test.GetName(),
+		Duration: time.Since(start),
+		Note:     note,
+		Err:      err,
+	}
+}
+
+// printTAP writes results to stdout as a TAP13 stream, since each
+// SimpleTest is independent and doesn't need its plan line up front.
+func printTAP(results []runResult) {
+	fmt.Println("TAP version 13")
+	for index, result := range results {
+		status := "ok"
+		if result.Err != nil {
+			status = "not ok"
+		}
+		fmt.Printf("%s %d - %s/%s\n", status, index+1, result.Section, result.Name)
+		if result.Err != nil {
+			fmt.Printf("  ---\n  message: %q\n  ...\n", result.Err.Error())
+		}
+		if result.Note != "" {
+			fmt.Printf("# %s\n", result.Note)
+		}
+	}
+	fmt.Printf("1..%d\n", len(results))
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestcase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestsuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Testcases []junitTestcase `xml:"testcase"`
+}
+
+// writeJUnit renders results as a JUnit-XML report at path, giving
+// downstream implementations a single reproducible oracle report to
+// diff CI runs against.
+func writeJUnit(path string, results []runResult) error {
+	suite := junitTestsuite{Name: "cel-spec-conformance"}
+	for _, result := range results {
+		testcase := junitTestcase{
+			ClassName: result.Section,
+			Name:      result.Name,
+			Time:      result.Duration.Seconds(),
+			SystemOut: result.Note,
+		}
+		suite.Tests++
+		if result.Err != nil {
+			suite.Failures++
+			testcase.Failure = &junitFailure{Message: result.Err.Error(), Text: result.Err.Error()}
+		}
+		suite.Testcases = append(suite.Testcases, testcase)
+	}
+
+	data, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append([]byte(xml.Header), data...), 0644)
+}
+
+// run_conformance evaluates cases against cel-go using up to
+// parallel_workers concurrent workers (each SimpleTest is independent,
+// so this is a plain bounded worker pool), then reports the outcome as
+// TAP on stdout and as a JUnit-XML report at junit_output.
+func run_conformance(cases []runCase) {
+	results := make([]runResult, len(cases))
+
+	workers := parallel_workers
+	if workers < 1 {
+		workers = 1
+	}
+	semaphore := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for index, testCase := range cases {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(index int, testCase runCase) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[index] = evalOne(testCase)
+		}(index, testCase)
+	}
+	wg.Wait()
+
+	printTAP(results)
+	if err := writeJUnit(junit_output, results); err != nil {
+		panic(err)
+	}
+}
+
+// celValue renders a cel.expr.Value exactly as CEL source would write it
+// (42u, 42, "x", b"\x00", [1, 2], {"k": "v"}, TypeName{field: value}, ...),
+// so a Gherkin step carries the typed literal instead of Go's %v
+// formatting of the underlying oneof wrapper.
+func celValue(value *exprpb.Value) string {
+	if value == nil {
+		return "null"
+	}
+	switch kind := value.GetKind().(type) {
+	case *exprpb.Value_NullValue:
+		return "null"
+	case *exprpb.Value_BoolValue:
+		return strconv.FormatBool(kind.BoolValue)
+	case *exprpb.Value_Int64Value:
+		return strconv.FormatInt(kind.Int64Value, 10)
+	case *exprpb.Value_Uint64Value:
+		return strconv.FormatUint(kind.Uint64Value, 10) + "u"
+	case *exprpb.Value_DoubleValue:
+		literal := strconv.FormatFloat(kind.DoubleValue, 'g', -1, 64)
+		if !strings.ContainsAny(literal, ".eEnN") {
+			literal += ".0"
+		}
+		return literal
+	case *exprpb.Value_StringValue:
+		return strconv.Quote(kind.StringValue)
+	case *exprpb.Value_BytesValue:
+		return "b" + strconv.Quote(string(kind.BytesValue))
+	case *exprpb.Value_ListValue:
+		elements := make([]string, 0, len(kind.ListValue.GetValues()))
+		for _, element := range kind.ListValue.GetValues() {
+			elements = append(elements, celValue(element))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *exprpb.Value_MapValue:
+		entries := make([]string, 0, len(kind.MapValue.GetEntries()))
+		for _, entry := range kind.MapValue.GetEntries() {
+			entries = append(entries, fmt.Sprintf("%s: %s", celValue(entry.GetKey()), celValue(entry.GetValue())))
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case *exprpb.Value_ObjectValue:
+		return celObjectValue(kind.ObjectValue)
+	case *exprpb.Value_TypeValue:
+		return kind.TypeValue
+	case *exprpb.Value_EnumValue:
+		return celEnumValueLiteral(kind.EnumValue)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// celEnumValueLiteral renders an EnumValue as "TypeName.NAME", looking the
+// name up by number through the global proto registry rather than
+// printing the raw number, the same way reflectScalarLiteral's EnumKind
+// case resolves message-field enums.
+func celEnumValueLiteral(enumValue *exprpb.EnumValue) string {
+	descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(enumValue.GetType()))
+	if err == nil {
+		if enumDescriptor, ok := descriptor.(protoreflect.EnumDescriptor); ok {
+			if value := enumDescriptor.Values().ByNumber(protoreflect.EnumNumber(enumValue.GetValue())); value != nil {
+				return fmt.Sprintf("%s.%s", enumValue.GetType(), value.Name())
+			}
+		}
+	}
+	return fmt.Sprintf("%s.%d", enumValue.GetType(), enumValue.GetValue())
+}
+
+// celObjectValue renders a message literal's packed Any as
+// "TypeName{field: value, ...}". It unmarshals through the global proto
+// registry and walks the result with protoreflect rather than going
+// through protojson, because protojson encodes int64/uint64/fixed64
+// fields (and bytes, and non-finite doubles) as JSON strings: rendering
+// those through a JSON decode would lose the distinction between a
+// numeric field and an actual CEL string literal.
+func celObjectValue(message *anypb.Any) string {
+	inner, err := message.UnmarshalNew()
+	if err != nil {
+		return fmt.Sprintf("%v", message)
+	}
+	return reflectMessageLiteral(inner.ProtoReflect())
+}
+
+// reflectMessageLiteral renders a populated message as
+// "TypeName{field: value, ...}", with fields sorted for stable output.
+func reflectMessageLiteral(message protoreflect.Message) string {
+	typeName := string(message.Descriptor().FullName())
+	var fieldLiterals []string
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		fieldLiterals = append(fieldLiterals, fmt.Sprintf("%s: %s", field.Name(), reflectFieldLiteral(field, value)))
+		return true
+	})
+	sort.Strings(fieldLiterals)
+	return fmt.Sprintf("%s{%s}", typeName, strings.Join(fieldLiterals, ", "))
+}
+
+// reflectFieldLiteral renders one field's value, expanding list and map
+// fields element-by-element through reflectScalarLiteral so each element
+// keeps its own Kind.
+func reflectFieldLiteral(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch {
+	case field.IsList():
+		list := value.List()
+		elements := make([]string, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			elements = append(elements, reflectScalarLiteral(field, list.Get(i)))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case field.IsMap():
+		mapValue := value.Map()
+		entries := make([]string, 0, mapValue.Len())
+		mapValue.Range(func(key protoreflect.MapKey, entryValue protoreflect.Value) bool {
+			entries = append(entries, fmt.Sprintf("%s: %s", reflectScalarLiteral(field.MapKey(), key.Value()), reflectScalarLiteral(field.MapValue(), entryValue)))
+			return true
+		})
+		sort.Strings(entries)
+		return "{" + strings.Join(entries, ", ") + "}"
+	default:
+		return reflectScalarLiteral(field, value)
+	}
+}
+
+// reflectScalarLiteral renders a single scalar/message value as a CEL
+// literal, dispatching on the field's declared Kind so 64-bit integer
+// fields render as int/uint literals instead of the JSON strings
+// protojson would have encoded them as.
+func reflectScalarLiteral(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(value.Bool())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(value.Int(), 10)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(value.Uint(), 10) + "u"
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		literal := strconv.FormatFloat(value.Float(), 'g', -1, 64)
+		if !strings.ContainsAny(literal, ".eEnN") {
+			literal += ".0"
+		}
+		return literal
+	case protoreflect.StringKind:
+		return strconv.Quote(value.String())
+	case protoreflect.BytesKind:
+		return "b" + strconv.Quote(string(value.Bytes()))
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.FormatInt(int64(value.Enum()), 10)
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return reflectMessageLiteral(value.Message())
+	}
+	return fmt.Sprintf("%v", value.Interface())
+}
+
+// celType renders a type_env Decl's declared type as CEL-style type syntax
+// with primitives named by their lower-cased proto enum names (e.g.
+// "int64", "list(string)", "map(string, int64)"), so the `Given
+// type_env parameter ... is ...` step carries a type a step-definition
+// layer can parse directly.
+func celType(decl *exprpb.Decl) string {
+	if ident := decl.GetIdent(); ident != nil {
+		return celTypeLiteral(ident.GetType())
+	}
+	if fn := decl.GetFunction(); fn != nil {
+		return fmt.Sprintf("func(%s)", decl.GetName())
+	}
+	return "dyn"
+}
+
+func celTypeLiteral(t *exprpb.Type) string {
+	if t == nil {
+		return "dyn"
+	}
+	switch kind := t.GetTypeKind().(type) {
+	case *exprpb.Type_Dyn:
+		return "dyn"
+	case *exprpb.Type_Null:
+		return "null_type"
+	case *exprpb.Type_Primitive:
+		return strings.ToLower(kind.Primitive.String())
+	case *exprpb.Type_Wrapper:
+		return fmt.Sprintf("wrapper(%s)", strings.ToLower(kind.Wrapper.String()))
+	case *exprpb.Type_ListType_:
+		return fmt.Sprintf("list(%s)", celTypeLiteral(kind.ListType.GetElemType()))
+	case *exprpb.Type_MapType_:
+		return fmt.Sprintf("map(%s, %s)", celTypeLiteral(kind.MapType.GetKeyType()), celTypeLiteral(kind.MapType.GetValueType()))
+	case *exprpb.Type_MessageType:
+		return kind.MessageType
+	case *exprpb.Type_TypeParam:
+		return kind.TypeParam
+	case *exprpb.Type_Type:
+		return fmt.Sprintf("type(%s)", celTypeLiteral(kind.Type))
+	}
+	return "dyn"
+}
+
+// celBinding renders a Bindings map entry's ExprValue the same way
+// celValue renders an expected result, so `Given bindings parameter ...`
+// and `Then value is ...` steps use one consistent literal syntax.
+func celBinding(binding *exprpb.ExprValue) string {
+	return celValue(binding.GetValue())
+}
+
+func gherkin_testfile(testFile *spb.SimpleTestFile) {
 	const test_template = `
 Feature: {{.Name}}
          {{.Description}}
@@ -91,20 +883,19 @@ Scenario: {{.Name}}
 {{end}}
 {{- if .TypeEnv}}
 {{- range $index, $env := .TypeEnv}}
-   Given type_env parameter {{printf "%q" $env.Name}} is {{printf "%v" $env.GetDeclKind}}
+   Given type_env parameter {{printf "%q" $env.Name}} is {{celType $env}}
 {{end -}}
 {{end}}
 {{- if .Bindings}}
 {{- range $key, $value := .Bindings}}
-   Given bindings parameter {{printf "%q" $key}} is {{printf "%v" $value.GetValue}}
+   Given bindings parameter {{printf "%q" $key}} is {{celBinding $value}}
 {{end -}}
 {{end}}
 {{- if .Container}}
    Given container is {{printf "%q" .Container}}
 {{end}}
     When CEL expression {{printf "%q" .Expr}} is evaluated
-{{if .GetValue}}    Then value is {{printf "%v" .GetValue}}{{end -}}
-{{if .GetEvalError}}    Then eval_error is {{printf "%v" .GetEvalError}}{{end -}}
+{{resultStep .}}
 
     {{- /* Then JSON value is {{json .ResultMatcher | printf "%s" */}}
 
@@ -112,7 +903,11 @@ Scenario: {{.Name}}
 {{end}}
 `
 	func_map := template.FuncMap{
-		"json": json.Marshal,
+		"json":       json.Marshal,
+		"resultStep": resultStep,
+		"celValue":   celValue,
+		"celType":    celType,
+		"celBinding": celBinding,
 	}
 	gherkin_template := template.Must(template.New("gherkin").Funcs(func_map).Parse(test_template))
 	err := gherkin_template.Execute(os.Stdout, testFile)
@@ -122,16 +917,29 @@ Scenario: {{.Name}}
 }
 
 var json_format bool
+var yaml_format bool
 var gherkin_format bool
+var run_mode bool
+var parallel_workers int
+var junit_output string
 
 func init() {
 	flag.BoolVar(&json_format, "json", false, "JSON-format output")
+	flag.BoolVar(&yaml_format, "yaml", false, "YAML-format output, one structured document per scenario")
 	flag.BoolVar(&gherkin_format, "gherkin", true, "Gherkin-format output")
+	flag.BoolVar(&run_mode, "run", false, "Execute each SimpleTest against cel-go and report the outcome as TAP + JUnit-XML")
+	flag.IntVar(&parallel_workers, "parallel", 1, "Number of SimpleTests to evaluate concurrently in -run mode")
+	flag.StringVar(&junit_output, "junit-output", "junit.xml", "Where to write the JUnit-XML report produced by -run mode")
 }
 
 func main() {
 	flag.Parse()
-	for _, input_file := range flag.Args() {
+	input_files, err := expandInputs(flag.Args())
+	if err != nil {
+		panic(err)
+	}
+	var run_cases []runCase
+	for _, input_file := range input_files {
 		fmt.Fprintf(os.Stderr, "Reading %v\n", input_file)
 		pb, err := parseSimpleFile(input_file)
 		if err != nil {
@@ -143,5 +951,14 @@ func main() {
 		if json_format {
 			json_testfile(pb)
 		}
+		if yaml_format {
+			yaml_testfile(pb)
+		}
+		if run_mode {
+			run_cases = append(run_cases, collectCases(pb)...)
+		}
+	}
+	if run_mode {
+		run_conformance(run_cases)
 	}
 }
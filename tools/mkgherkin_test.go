@@ -0,0 +1,405 @@
+// SPDX-Copyright: Copyright (c) Capital One Services, LLC
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2020 Capital One Services, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+
+	spb "github.com/google/cel-spec/proto/test/v1/testpb"
+)
+
+func TestCelValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *exprpb.Value
+		want  string
+	}{
+		{
+			name:  "null",
+			value: &exprpb.Value{Kind: &exprpb.Value_NullValue{}},
+			want:  "null",
+		},
+		{
+			name:  "bool",
+			value: &exprpb.Value{Kind: &exprpb.Value_BoolValue{BoolValue: true}},
+			want:  "true",
+		},
+		{
+			name:  "int64",
+			value: &exprpb.Value{Kind: &exprpb.Value_Int64Value{Int64Value: 42}},
+			want:  "42",
+		},
+		{
+			name:  "uint64",
+			value: &exprpb.Value{Kind: &exprpb.Value_Uint64Value{Uint64Value: 42}},
+			want:  "42u",
+		},
+		{
+			name:  "double",
+			value: &exprpb.Value{Kind: &exprpb.Value_DoubleValue{DoubleValue: 1.5}},
+			want:  "1.5",
+		},
+		{
+			name:  "string",
+			value: &exprpb.Value{Kind: &exprpb.Value_StringValue{StringValue: "x"}},
+			want:  `"x"`,
+		},
+		{
+			name:  "bytes",
+			value: &exprpb.Value{Kind: &exprpb.Value_BytesValue{BytesValue: []byte{0}}},
+			want:  `b"\x00"`,
+		},
+		{
+			name: "list",
+			value: &exprpb.Value{Kind: &exprpb.Value_ListValue{ListValue: &exprpb.ListValue{
+				Values: []*exprpb.Value{
+					{Kind: &exprpb.Value_Int64Value{Int64Value: 1}},
+					{Kind: &exprpb.Value_Int64Value{Int64Value: 2}},
+				},
+			}}},
+			want: "[1, 2]",
+		},
+		{
+			name: "map",
+			value: &exprpb.Value{Kind: &exprpb.Value_MapValue{MapValue: &exprpb.MapValue{
+				Entries: []*exprpb.MapValue_Entry{
+					{
+						Key:   &exprpb.Value{Kind: &exprpb.Value_StringValue{StringValue: "k"}},
+						Value: &exprpb.Value{Kind: &exprpb.Value_StringValue{StringValue: "v"}},
+					},
+				},
+			}}},
+			want: `{"k": "v"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := celValue(tt.value); got != tt.want {
+				t.Errorf("celValue(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCelValueNil(t *testing.T) {
+	if got := celValue(nil); got != "null" {
+		t.Errorf("celValue(nil) = %q, want %q", got, "null")
+	}
+}
+
+func TestCelTypeLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  *exprpb.Type
+		want string
+	}{
+		{
+			name: "nil",
+			typ:  nil,
+			want: "dyn",
+		},
+		{
+			name: "dyn",
+			typ:  &exprpb.Type{TypeKind: &exprpb.Type_Dyn{}},
+			want: "dyn",
+		},
+		{
+			name: "primitive",
+			typ:  &exprpb.Type{TypeKind: &exprpb.Type_Primitive{Primitive: exprpb.Type_INT64}},
+			want: "int64",
+		},
+		{
+			name: "list",
+			typ: &exprpb.Type{TypeKind: &exprpb.Type_ListType_{ListType: &exprpb.Type_ListType{
+				ElemType: &exprpb.Type{TypeKind: &exprpb.Type_Primitive{Primitive: exprpb.Type_STRING}},
+			}}},
+			want: "list(string)",
+		},
+		{
+			name: "map",
+			typ: &exprpb.Type{TypeKind: &exprpb.Type_MapType_{MapType: &exprpb.Type_MapType{
+				KeyType:   &exprpb.Type{TypeKind: &exprpb.Type_Primitive{Primitive: exprpb.Type_STRING}},
+				ValueType: &exprpb.Type{TypeKind: &exprpb.Type_Primitive{Primitive: exprpb.Type_INT64}},
+			}}},
+			want: "map(string, int64)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := celTypeLiteral(tt.typ); got != tt.want {
+				t.Errorf("celTypeLiteral(%v) = %q, want %q", tt.typ, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultExpect(t *testing.T) {
+	test := &spb.SimpleTest{
+		Name: "has_value",
+		ResultMatcher: &spb.SimpleTest_Value{
+			Value: &exprpb.Value{Kind: &exprpb.Value_Int64Value{Int64Value: 42}},
+		},
+	}
+	expect, err := resultExpect(test)
+	if err != nil {
+		t.Fatalf("resultExpect() unexpected error: %v", err)
+	}
+	if want := map[string]interface{}{"int64Value": "42"}; !reflect.DeepEqual(expect.Value, want) {
+		t.Errorf("resultExpect().Value = %v, want %v", expect.Value, want)
+	}
+
+	anyErrors := &spb.SimpleTest{
+		Name: "has_any_eval_errors",
+		ResultMatcher: &spb.SimpleTest_AnyEvalErrors{
+			AnyEvalErrors: &spb.ErrorSetMatcher{Errors: []*exprpb.ErrorSet{
+				{Errors: []*statuspb.Status{{Message: "no such overload"}}},
+			}},
+		},
+	}
+	expect, err = resultExpect(anyErrors)
+	if err != nil {
+		t.Fatalf("resultExpect() unexpected error: %v", err)
+	}
+	if expect.EvalError == nil || len(expect.EvalError.Errors) != 1 {
+		t.Errorf("resultExpect().EvalError = %v, want one error", expect.EvalError)
+	}
+
+	if _, err := resultExpect(&spb.SimpleTest{Name: "no_matcher"}); err == nil {
+		t.Error("resultExpect() with no result_matcher: want error, got nil")
+	}
+}
+
+func TestResultStep(t *testing.T) {
+	tests := []struct {
+		name   string
+		test   *spb.SimpleTest
+		prefix string
+		substr string
+	}{
+		{
+			name:   "value",
+			test:   &spb.SimpleTest{ResultMatcher: &spb.SimpleTest_Value{Value: &exprpb.Value{Kind: &exprpb.Value_Int64Value{Int64Value: 42}}}},
+			prefix: "    Then value is",
+			substr: "42",
+		},
+		{
+			name: "any_eval_errors",
+			test: &spb.SimpleTest{ResultMatcher: &spb.SimpleTest_AnyEvalErrors{
+				AnyEvalErrors: &spb.ErrorSetMatcher{Errors: []*exprpb.ErrorSet{
+					{Errors: []*statuspb.Status{{Message: "no such overload"}}},
+				}},
+			}},
+			prefix: "    Then eval_error is one of",
+			substr: "no such overload",
+		},
+		{
+			name:   "unknown",
+			test:   &spb.SimpleTest{ResultMatcher: &spb.SimpleTest_Unknown{Unknown: &exprpb.UnknownSet{Exprs: []int64{1}}}},
+			prefix: "    Then unknown is",
+			substr: "1",
+		},
+		{
+			name: "any_unknowns",
+			test: &spb.SimpleTest{ResultMatcher: &spb.SimpleTest_AnyUnknowns{
+				AnyUnknowns: &spb.UnknownSetMatcher{Unknowns: []*exprpb.UnknownSet{{Exprs: []int64{1}}}},
+			}},
+			prefix: "    Then any_unknown is one of",
+			substr: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resultStep(tt.test)
+			if err != nil {
+				t.Fatalf("resultStep() unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(got, tt.prefix) || !strings.Contains(got, tt.substr) {
+				t.Errorf("resultStep() = %q, want prefix %q and substring %q", got, tt.prefix, tt.substr)
+			}
+		})
+	}
+
+	if _, err := resultStep(&spb.SimpleTest{}); err == nil {
+		t.Error("resultStep() with no result_matcher: want error, got nil")
+	}
+}
+
+func TestInputFormatFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		override string
+		want     string
+		wantErr  bool
+	}{
+		{name: "textproto", filename: "x.textproto", want: "prototext"},
+		{name: "pb.txt", filename: "x.pb.txt", want: "prototext"},
+		{name: "binarypb", filename: "x.binarypb", want: "binarypb"},
+		{name: "pb", filename: "x.pb", want: "binarypb"},
+		{name: "json", filename: "x.json", want: "json"},
+		{name: "override wins over extension", filename: "x.json", override: "prototext", want: "prototext"},
+		{name: "unrecognized extension", filename: "x.txt", wantErr: true},
+		{name: "stdin without an override", filename: "-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := input_format
+			defer func() { input_format = saved }()
+			input_format = tt.override
+
+			got, err := inputFormatFor(tt.filename)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("inputFormatFor(%q) = %q, want an error", tt.filename, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("inputFormatFor(%q) unexpected error: %v", tt.filename, err)
+			}
+			if got != tt.want {
+				t.Errorf("inputFormatFor(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.textproto", "b.textproto"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := expandInputs([]string{
+		"-",
+		filepath.Join(dir, "*.textproto"),
+		filepath.Join(dir, "missing-*.textproto"),
+	})
+	if err != nil {
+		t.Fatalf("expandInputs() unexpected error: %v", err)
+	}
+
+	want := []string{
+		"-",
+		filepath.Join(dir, "a.textproto"),
+		filepath.Join(dir, "b.textproto"),
+		filepath.Join(dir, "missing-*.textproto"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandInputs() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckResultValue(t *testing.T) {
+	test := &spb.SimpleTest{
+		ResultMatcher: &spb.SimpleTest_Value{
+			Value: &exprpb.Value{Kind: &exprpb.Value_Int64Value{Int64Value: 42}},
+		},
+	}
+
+	if _, err := checkResult(test, types.Int(42), nil); err != nil {
+		t.Errorf("checkResult() matching value: unexpected error: %v", err)
+	}
+	if _, err := checkResult(test, types.Int(7), nil); err == nil {
+		t.Error("checkResult() mismatched value: want error, got nil")
+	}
+	if _, err := checkResult(test, nil, errors.New("boom")); err == nil {
+		t.Error("checkResult() expected a value but evaluation errored: want error, got nil")
+	}
+}
+
+func TestCheckResultEvalError(t *testing.T) {
+	test := &spb.SimpleTest{
+		ResultMatcher: &spb.SimpleTest_EvalError{
+			EvalError: &exprpb.ErrorSet{Errors: []*statuspb.Status{
+				{Message: "no such overload"},
+			}},
+		},
+	}
+
+	if _, err := checkResult(test, nil, errors.New("no such overload: foo")); err != nil {
+		t.Errorf("checkResult() matching eval_error message: unexpected error: %v", err)
+	}
+	if _, err := checkResult(test, nil, errors.New("unbound variable")); err == nil {
+		t.Error("checkResult() wrong eval_error message: want error, got nil")
+	}
+	if _, err := checkResult(test, types.Int(0), nil); err == nil {
+		t.Error("checkResult() expected eval_error but evaluation succeeded: want error, got nil")
+	}
+}
+
+func TestCheckResultAnyEvalErrorsNotesPartialMatch(t *testing.T) {
+	test := &spb.SimpleTest{
+		ResultMatcher: &spb.SimpleTest_AnyEvalErrors{
+			AnyEvalErrors: &spb.ErrorSetMatcher{Errors: []*exprpb.ErrorSet{
+				{Errors: []*statuspb.Status{{Message: "no such overload"}}},
+				{Errors: []*statuspb.Status{{Message: "unbound variable"}}},
+			}},
+		},
+	}
+
+	note, err := checkResult(test, nil, errors.New("some other error"))
+	if err != nil {
+		t.Errorf("checkResult() any_eval_errors: unexpected error: %v", err)
+	}
+	if note == "" {
+		t.Error("checkResult() any_eval_errors: want a note that the match was only checked loosely, got none")
+	}
+}
+
+func TestBuildEnvRegistersTypeEnvDecls(t *testing.T) {
+	test := &spb.SimpleTest{
+		Expr: "greet(name)",
+		TypeEnv: []*exprpb.Decl{
+			{
+				Name: "name",
+				DeclKind: &exprpb.Decl_Ident{
+					Ident: &exprpb.Decl_IdentDecl{Type: decls.String},
+				},
+			},
+			{
+				Name: "greet",
+				DeclKind: &exprpb.Decl_Function{
+					Function: &exprpb.Decl_FunctionDecl{
+						Overloads: []*exprpb.Decl_FunctionDecl_Overload{
+							decls.NewOverload("greet_string", []*exprpb.Type{decls.String}, decls.String),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	env, err := buildEnv(test)
+	if err != nil {
+		t.Fatalf("buildEnv() unexpected error: %v", err)
+	}
+	if _, iss := env.Compile(test.GetExpr()); iss != nil && iss.Err() != nil {
+		t.Errorf("compiling %q against the built env: %v (function type_env Decls must be registered, not just idents)", test.GetExpr(), iss.Err())
+	}
+}